@@ -0,0 +1,62 @@
+package encryption
+
+import "testing"
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	key := GenerateRandomBytes(32)
+	message := []byte("the quick brown fox")
+
+	ciphertext, err := Encrypt(key, message)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	plaintext, err := Decrypt(key, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if string(plaintext) != string(message) {
+		t.Fatalf("got %q want %q", plaintext, message)
+	}
+}
+
+func TestDecryptDetectsTampering(t *testing.T) {
+	key := GenerateRandomBytes(32)
+	ciphertext, err := Encrypt(key, []byte("the quick brown fox"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	ciphertext[len(ciphertext)-1] ^= 0xFF
+
+	if _, err := Decrypt(key, ciphertext); err != ErrUnauthenticated {
+		t.Fatalf("Decrypt of tampered ciphertext: got err %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	ciphertext, err := Encrypt(GenerateRandomBytes(32), []byte("message"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := Decrypt(GenerateRandomBytes(32), ciphertext); err != ErrUnauthenticated {
+		t.Fatalf("Decrypt with wrong key: got err %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestAEADSealOpenWithAES256GCM(t *testing.T) {
+	key := GenerateRandomBytes(32)
+	aead, err := NewAEAD(key, CipherAES256GCM)
+	if err != nil {
+		t.Fatalf("NewAEAD: %v", err)
+	}
+	sealed, err := aead.Seal([]byte("payload"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	opened, err := aead.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if string(opened) != "payload" {
+		t.Fatalf("got %q want %q", opened, "payload")
+	}
+}
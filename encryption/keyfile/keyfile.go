@@ -0,0 +1,196 @@
+// Package keyfile implements a password-wrapped master-key subsystem
+// modeled on restic's key file: a randomly generated master key pair
+// (used for bulk Encrypt/Hmac) is itself encrypted under a password-
+// derived key, so that the password can be rotated, or multiple
+// passwords can unlock the same master key, without touching any data
+// already encrypted under it.
+package keyfile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/wheelcomplex/go-hash/encryption"
+)
+
+// ErrKeyNotOpen is returned by methods that need the decrypted master
+// key (AddPassword, ChangePassword) when called on a Key obtained via
+// json.Unmarshal rather than CreateKey/OpenKey.
+var ErrKeyNotOpen = errors.New("keyfile: key has not been decrypted")
+
+// masterKey is the secret wrapped by a Key. Sign and Encrypt are
+// independent keys so that the same master key can be used both with
+// encryption.Hmac and with encryption.Encrypt/AEAD without key reuse
+// across algorithms.
+type masterKey struct {
+	Sign    []byte `json:"sign"`
+	Encrypt []byte `json:"encrypt"`
+}
+
+// Key is the on-disk representation of a password-wrapped master key,
+// serializable with encoding/json. Multiple Keys (e.g. one per user, or
+// one per password) can independently wrap the same master key; see
+// AddPassword.
+type Key struct {
+	Created  time.Time         `json:"created"`
+	Username string            `json:"username"`
+	Hostname string            `json:"hostname"`
+	KDF      string            `json:"kdf"`
+	Params   encryption.Params `json:"params"`
+	Salt     []byte            `json:"salt"`
+	Data     []byte            `json:"data"`
+
+	master *masterKey
+}
+
+// CreateKey generates a new random master key and wraps it with a key
+// derived from password, returning a Key ready to be marshaled to JSON
+// and persisted.
+func CreateKey(password string) (*Key, error) {
+	mk := &masterKey{
+		Sign:    encryption.GenerateRandomBytes(encryption.KEYLEN),
+		Encrypt: encryption.GenerateRandomBytes(encryption.KEYLEN),
+	}
+	return wrapMasterKey(mk, password)
+}
+
+// OpenKey parses a JSON Key blob and decrypts its master key using
+// password. It returns encryption.ErrUnauthenticated if password is
+// wrong.
+func OpenKey(blob []byte, password string) (*Key, error) {
+	var k Key
+	if err := json.Unmarshal(blob, &k); err != nil {
+		return nil, err
+	}
+	mk, err := unwrapMasterKey(&k, password)
+	if err != nil {
+		return nil, err
+	}
+	k.master = mk
+	return &k, nil
+}
+
+// Marshal serializes k to JSON for persistence.
+func (k *Key) Marshal() ([]byte, error) {
+	return json.Marshal(k)
+}
+
+// SignKey returns the decrypted key to use with encryption.Hmac. It
+// panics if k was not produced by CreateKey or OpenKey.
+func (k *Key) SignKey() []byte {
+	if k.master == nil {
+		panic(ErrKeyNotOpen)
+	}
+	return k.master.Sign
+}
+
+// EncryptKey returns the decrypted key to use with encryption.Encrypt
+// or encryption.NewAEAD. It panics if k was not produced by CreateKey
+// or OpenKey.
+func (k *Key) EncryptKey() []byte {
+	if k.master == nil {
+		panic(ErrKeyNotOpen)
+	}
+	return k.master.Encrypt
+}
+
+// AddPassword wraps k's master key under an additional password,
+// returning a new Key that independently unlocks the same master key.
+// Both Keys should be persisted; neither needs the other to open data
+// encrypted under the shared master key.
+func (k *Key) AddPassword(password string) (*Key, error) {
+	if k.master == nil {
+		return nil, ErrKeyNotOpen
+	}
+	return wrapMasterKey(k.master, password)
+}
+
+// VerifyPassword reports whether password unlocks k, without modifying
+// k or any other Key wrapping the same master key. This is a pure
+// check; to actually revoke a password, remove its Key from the
+// Keyring that tracks the set of Keys wrapping a given master key.
+func (k *Key) VerifyPassword(password string) (bool, error) {
+	_, err := unwrapMasterKey(k, password)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, encryption.ErrUnauthenticated):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// ChangePassword returns a new Key wrapping k's master key under
+// newPassword. The returned Key should replace k in storage; data
+// already encrypted under the master key does not need to be
+// re-encrypted.
+func (k *Key) ChangePassword(newPassword string) (*Key, error) {
+	if k.master == nil {
+		return nil, ErrKeyNotOpen
+	}
+	return wrapMasterKey(k.master, newPassword)
+}
+
+func wrapMasterKey(mk *masterKey, password string) (*Key, error) {
+	params := encryption.DefaultParams()
+	salt := encryption.GenerateRandomBytes(params.SaltLen)
+	hasher := &encryption.PasswordHasher{Params: params}
+	kek := hasher.DeriveKey(password, salt)
+
+	aead, err := encryption.NewAEAD(kek, encryption.CipherXChaCha20Poly1305)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(mk)
+	if err != nil {
+		return nil, err
+	}
+	data, err := aead.Seal(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	username, hostname := currentIdentity()
+	return &Key{
+		Created:  time.Now(),
+		Username: username,
+		Hostname: hostname,
+		KDF:      string(params.Variant),
+		Params:   params,
+		Salt:     salt,
+		Data:     data,
+		master:   mk,
+	}, nil
+}
+
+func unwrapMasterKey(k *Key, password string) (*masterKey, error) {
+	hasher := &encryption.PasswordHasher{Params: k.Params}
+	kek := hasher.DeriveKey(password, k.Salt)
+
+	aead, err := encryption.NewAEAD(kek, encryption.CipherXChaCha20Poly1305)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(k.Data)
+	if err != nil {
+		return nil, err
+	}
+	var mk masterKey
+	if err := json.Unmarshal(plaintext, &mk); err != nil {
+		return nil, err
+	}
+	return &mk, nil
+}
+
+func currentIdentity() (username, hostname string) {
+	hostname, _ = os.Hostname()
+	username = "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		username = u.Username
+	}
+	return username, hostname
+}
@@ -0,0 +1,112 @@
+package keyfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCreateOpenRoundTrip(t *testing.T) {
+	k, err := CreateKey("hunter2")
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	blob, err := k.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	opened, err := OpenKey(blob, "hunter2")
+	if err != nil {
+		t.Fatalf("OpenKey: %v", err)
+	}
+	if !bytes.Equal(opened.EncryptKey(), k.EncryptKey()) || !bytes.Equal(opened.SignKey(), k.SignKey()) {
+		t.Fatal("OpenKey did not recover the same master key")
+	}
+
+	if _, err := OpenKey(blob, "wrong password"); err == nil {
+		t.Fatal("OpenKey succeeded with the wrong password")
+	}
+}
+
+func TestAddChangePassword(t *testing.T) {
+	k, err := CreateKey("hunter2")
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+
+	added, err := k.AddPassword("second")
+	if err != nil {
+		t.Fatalf("AddPassword: %v", err)
+	}
+	addedBlob, err := added.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	openedViaSecond, err := OpenKey(addedBlob, "second")
+	if err != nil {
+		t.Fatalf("OpenKey with added password: %v", err)
+	}
+	if !bytes.Equal(openedViaSecond.EncryptKey(), k.EncryptKey()) {
+		t.Fatal("AddPassword wrapped a different master key")
+	}
+
+	changed, err := k.ChangePassword("third")
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	changedBlob, err := changed.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if _, err := OpenKey(changedBlob, "hunter2"); err == nil {
+		t.Fatal("OpenKey succeeded with the password ChangePassword replaced")
+	}
+	openedViaThird, err := OpenKey(changedBlob, "third")
+	if err != nil {
+		t.Fatalf("OpenKey with new password: %v", err)
+	}
+	if !bytes.Equal(openedViaThird.EncryptKey(), k.EncryptKey()) {
+		t.Fatal("ChangePassword rewrapped a different master key")
+	}
+}
+
+func TestKeyringAddRemove(t *testing.T) {
+	k, err := CreateKey("hunter2")
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	blob, err := k.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	kr := NewKeyring(k)
+	if _, err := kr.AddPassword("second"); err != nil {
+		t.Fatalf("Keyring.AddPassword: %v", err)
+	}
+	if len(kr.Keys()) != 2 {
+		t.Fatalf("keyring has %d keys, want 2", len(kr.Keys()))
+	}
+
+	if err := kr.RemovePassword("hunter2"); err != nil {
+		t.Fatalf("Keyring.RemovePassword: %v", err)
+	}
+	if len(kr.Keys()) != 1 {
+		t.Fatalf("keyring has %d keys after removal, want 1", len(kr.Keys()))
+	}
+	for _, remaining := range kr.Keys() {
+		if ok, _ := remaining.VerifyPassword("hunter2"); ok {
+			t.Fatal("a key remaining in the ring still unlocks with the removed password")
+		}
+	}
+
+	if err := kr.RemovePassword("hunter2"); err != ErrPasswordNotFound {
+		t.Fatalf("RemovePassword of an already-removed password: got %v, want ErrPasswordNotFound", err)
+	}
+
+	// The blob minted before removal is unaffected: Keyring tracks its
+	// own set, it does not revoke access to blobs outside it.
+	if _, err := OpenKey(blob, "hunter2"); err != nil {
+		t.Fatalf("OpenKey on the original blob after Keyring removal: %v", err)
+	}
+}
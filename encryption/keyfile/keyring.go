@@ -0,0 +1,71 @@
+package keyfile
+
+import "errors"
+
+// ErrPasswordNotFound is returned by Keyring.RemovePassword when no Key
+// in the ring unlocks with the given password.
+var ErrPasswordNotFound = errors.New("keyfile: no matching password in keyring")
+
+// Keyring tracks the set of Keys that independently wrap the same
+// master key, so that adding a password is paired with a real way to
+// revoke one: RemovePassword deletes the matching Key from the ring
+// rather than merely reporting whether it would unlock.
+type Keyring struct {
+	keys []*Key
+}
+
+// NewKeyring returns a Keyring tracking the given Keys, which must all
+// wrap the same master key (e.g. produced by CreateKey followed by
+// repeated AddPassword calls).
+func NewKeyring(keys ...*Key) *Keyring {
+	return &Keyring{keys: append([]*Key(nil), keys...)}
+}
+
+// Keys returns the Keys currently tracked by the ring, in the order
+// they were added. The caller should persist this set in place of
+// whatever it previously stored.
+func (kr *Keyring) Keys() []*Key {
+	return append([]*Key(nil), kr.keys...)
+}
+
+// AddPassword wraps the ring's master key under an additional password
+// and adds the resulting Key to the ring. It requires at least one Key
+// in the ring to already be open (i.e. produced by CreateKey or
+// OpenKey, not a bare json.Unmarshal).
+func (kr *Keyring) AddPassword(password string) (*Key, error) {
+	var opened *Key
+	for _, k := range kr.keys {
+		if k.master != nil {
+			opened = k
+			break
+		}
+	}
+	if opened == nil {
+		return nil, ErrKeyNotOpen
+	}
+	nk, err := opened.AddPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	kr.keys = append(kr.keys, nk)
+	return nk, nil
+}
+
+// RemovePassword finds the Key in the ring that unlocks with password
+// and removes it from the ring, so that password can no longer open
+// the master key via this Keyring. It returns ErrPasswordNotFound if no
+// Key matches. The master key itself, and any other Key wrapping it,
+// are unaffected.
+func (kr *Keyring) RemovePassword(password string) error {
+	for i, k := range kr.keys {
+		ok, err := k.VerifyPassword(password)
+		if err != nil {
+			return err
+		}
+		if ok {
+			kr.keys = append(kr.keys[:i:i], kr.keys[i+1:]...)
+			return nil
+		}
+	}
+	return ErrPasswordNotFound
+}
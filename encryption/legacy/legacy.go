@@ -0,0 +1,175 @@
+// Package legacy verifies password hashes produced by other systems
+// (bcrypt, scrypt, PBKDF2-SHA256, and Atheme-style crypt(3) MD5
+// hashes), so that applications migrating onto this module's Argon2id
+// hashing can accept a user's existing hash on their next login instead
+// of forcing a mass password reset. Verify reports whether the caller
+// should replace the stored hash with a fresh Argon2id one; MultiHasher
+// wraps that decision into a single authentication call site.
+package legacy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/wheelcomplex/go-hash/encryption"
+)
+
+// ErrUnknownFormat is returned by Verify when encoded does not match
+// any of the recognized legacy hash formats.
+var ErrUnknownFormat = errors.New("legacy: unrecognized hash format")
+
+// Verify checks password against encoded, a hash produced by another
+// system. It recognizes bcrypt ($2a$/$2b$/$2y$), scrypt ($scrypt$...),
+// PBKDF2-SHA256 ($pbkdf2-sha256$...), and Atheme-style crypt(3) MD5
+// ($1$...) hashes. needsRehash is true whenever ok is true: every
+// format handled here is weaker than this module's Argon2id, so a
+// successful legacy verification should always be followed by calling
+// encryption.PasswordHash (or PasswordHasher.HashPasswordEncoded) to
+// store a fresh hash.
+func Verify(encoded, password string) (ok bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return verifyBcrypt(encoded, password)
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return verifyScrypt(encoded, password)
+	case strings.HasPrefix(encoded, "$pbkdf2-sha256$"):
+		return verifyPBKDF2SHA256(encoded, password)
+	case strings.HasPrefix(encoded, "$1$"):
+		return verifyMD5Crypt(encoded, password)
+	default:
+		return false, false, ErrUnknownFormat
+	}
+}
+
+func verifyBcrypt(encoded, password string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, false, nil
+	default:
+		return false, false, err
+	}
+}
+
+// verifyScrypt checks a "$scrypt$ln=<N>,r=<r>,p=<p>$<b64 salt>$<b64 hash>"
+// hash, the format used by LXD for its scrypt-based password storage.
+func verifyScrypt(encoded, password string) (bool, bool, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 5 {
+		return false, false, fmt.Errorf("legacy: malformed scrypt hash")
+	}
+
+	var ln, r, p int
+	if _, err := fmt.Sscanf(fields[2], "ln=%d,r=%d,p=%d", &ln, &r, &p); err != nil {
+		return false, false, fmt.Errorf("legacy: malformed scrypt parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[3])
+	if err != nil {
+		return false, false, fmt.Errorf("legacy: malformed scrypt salt: %w", err)
+	}
+	want, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return false, false, fmt.Errorf("legacy: malformed scrypt hash: %w", err)
+	}
+
+	got, err := scrypt.Key([]byte(password), salt, 1<<uint(ln), r, p, len(want))
+	if err != nil {
+		return false, false, err
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+// verifyPBKDF2SHA256 checks a "$pbkdf2-sha256$<iterations>$<b64
+// salt>$<b64 hash>" hash, the PBKDF2-HMAC-SHA256 construction croc uses
+// to derive its transfer key.
+func verifyPBKDF2SHA256(encoded, password string) (bool, bool, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 5 {
+		return false, false, fmt.Errorf("legacy: malformed pbkdf2-sha256 hash")
+	}
+
+	var iterations int
+	if _, err := fmt.Sscanf(fields[2], "%d", &iterations); err != nil {
+		return false, false, fmt.Errorf("legacy: malformed pbkdf2-sha256 iteration count: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[3])
+	if err != nil {
+		return false, false, fmt.Errorf("legacy: malformed pbkdf2-sha256 salt: %w", err)
+	}
+	want, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return false, false, fmt.Errorf("legacy: malformed pbkdf2-sha256 hash: %w", err)
+	}
+
+	got := pbkdf2.Key([]byte(password), salt, iterations, len(want), sha256.New)
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+// verifyMD5Crypt checks an Atheme-style "$1$<salt>$<hash>" crypt(3) MD5
+// hash.
+func verifyMD5Crypt(encoded, password string) (bool, bool, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 4 {
+		return false, false, fmt.Errorf("legacy: malformed md5crypt hash")
+	}
+	salt := fields[2]
+	if subtle.ConstantTimeCompare([]byte(md5Crypt(password, salt)), []byte(encoded)) != 1 {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+// MultiHasher is a single authentication call site for applications
+// migrating legacy hashes to Argon2id: it checks a stored hash against
+// either format and, when the stored hash is legacy, returns a fresh
+// Argon2id hash for the caller to persist in its place.
+type MultiHasher struct {
+	*encryption.PasswordHasher
+}
+
+// NewMultiHasher returns a MultiHasher using encryption.NewPasswordHasher.
+func NewMultiHasher() *MultiHasher {
+	return &MultiHasher{encryption.NewPasswordHasher()}
+}
+
+// Authenticate checks password against storedHash, which may be an
+// Argon2 PHC string or any format recognized by Verify. If ok is true
+// and freshHash is non-empty, the caller should replace storedHash with
+// freshHash, a newly computed Argon2id PHC string.
+func (m *MultiHasher) Authenticate(password, storedHash string) (ok bool, freshHash string, err error) {
+	if strings.HasPrefix(storedHash, "$argon2i$") || strings.HasPrefix(storedHash, "$argon2id$") {
+		ok, err := m.VerifyPasswordEncoded(password, storedHash)
+		return ok, "", err
+	}
+
+	ok, needsRehash, err := Verify(storedHash, password)
+	if err != nil || !ok {
+		return false, "", err
+	}
+	if !needsRehash {
+		return true, "", nil
+	}
+	fresh, err := m.HashPasswordEncoded(password)
+	if err != nil {
+		// Authentication itself succeeded; failing to mint a fresh
+		// hash just means the caller keeps storedHash for now.
+		return true, "", nil
+	}
+	return true, fresh, nil
+}
@@ -0,0 +1,135 @@
+package legacy
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestVerifyBcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword: %v", err)
+	}
+
+	ok, needsRehash, err := Verify(string(hash), "hunter2")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok || !needsRehash {
+		t.Fatalf("Verify(correct password) = %v, %v, want true, true", ok, needsRehash)
+	}
+
+	ok, _, err = Verify(string(hash), "wrong")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if ok {
+		t.Fatal("Verify(wrong password) = true")
+	}
+}
+
+func TestVerifyScrypt(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	const ln, r, p = 10, 8, 1
+	hash, err := scrypt.Key([]byte("hunter2"), salt, 1<<ln, r, p, 32)
+	if err != nil {
+		t.Fatalf("scrypt.Key: %v", err)
+	}
+	encoded := fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", ln, r, p,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash))
+
+	ok, needsRehash, err := Verify(encoded, "hunter2")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok || !needsRehash {
+		t.Fatalf("Verify(correct password) = %v, %v, want true, true", ok, needsRehash)
+	}
+
+	if ok, _, err := Verify(encoded, "wrong"); err != nil || ok {
+		t.Fatalf("Verify(wrong password) = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyPBKDF2SHA256(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	const iterations = 1000
+	hash := pbkdf2.Key([]byte("hunter2"), salt, iterations, 32, sha256.New)
+	encoded := fmt.Sprintf("$pbkdf2-sha256$%d$%s$%s", iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash))
+
+	ok, needsRehash, err := Verify(encoded, "hunter2")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok || !needsRehash {
+		t.Fatalf("Verify(correct password) = %v, %v, want true, true", ok, needsRehash)
+	}
+
+	if ok, _, err := Verify(encoded, "wrong"); err != nil || ok {
+		t.Fatalf("Verify(wrong password) = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyMD5Crypt(t *testing.T) {
+	// Known-good vector cross-checked against `openssl passwd -1` and
+	// perl's crypt(): crypt("password", "$1$abcdefgh$").
+	const encoded = "$1$abcdefgh$G//4keteveJp0qb8z2DxG/"
+
+	ok, needsRehash, err := Verify(encoded, "password")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok || !needsRehash {
+		t.Fatalf("Verify(correct password) = %v, %v, want true, true", ok, needsRehash)
+	}
+
+	if ok, _, err := Verify(encoded, "wrong"); err != nil || ok {
+		t.Fatalf("Verify(wrong password) = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyUnknownFormat(t *testing.T) {
+	if _, _, err := Verify("not-a-recognized-hash", "password"); err != ErrUnknownFormat {
+		t.Fatalf("Verify of unrecognized format: got %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestMultiHasherAuthenticateRehashesLegacy(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword: %v", err)
+	}
+
+	m := NewMultiHasher()
+	ok, fresh, err := m.Authenticate("hunter2", string(hash))
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if !ok {
+		t.Fatal("Authenticate(correct password) = false")
+	}
+	if fresh == "" {
+		t.Fatal("Authenticate did not return a fresh Argon2id hash for a legacy hash")
+	}
+
+	// The fresh hash must itself verify, and not need a further rehash.
+	ok, fresh2, err := m.Authenticate("hunter2", fresh)
+	if err != nil {
+		t.Fatalf("Authenticate on fresh hash: %v", err)
+	}
+	if !ok {
+		t.Fatal("Authenticate(fresh hash, correct password) = false")
+	}
+	if fresh2 != "" {
+		t.Fatal("Authenticate asked to rehash an already-current Argon2id hash")
+	}
+}
@@ -0,0 +1,84 @@
+package legacy
+
+import "crypto/md5"
+
+// md5CryptAlphabet is the custom base64-like alphabet used by the
+// crypt(3) MD5 algorithm (and therefore by Atheme's "$1$" hashes), not
+// to be confused with standard base64.
+const md5CryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// md5Crypt implements the classic FreeBSD/crypt(3) MD5 password hash
+// used by, among others, Atheme's "$1$" account hashes. salt must not
+// contain "$". The returned string has the form "$1$salt$hash".
+func md5Crypt(password, salt string) string {
+	ctx := md5.New()
+	ctx.Write([]byte(password))
+	ctx.Write([]byte("$1$"))
+	ctx.Write([]byte(salt))
+
+	ctx1 := md5.New()
+	ctx1.Write([]byte(password))
+	ctx1.Write([]byte(salt))
+	ctx1.Write([]byte(password))
+	sum := ctx1.Sum(nil)
+
+	for pl := len(password); pl > 0; pl -= 16 {
+		n := pl
+		if n > 16 {
+			n = 16
+		}
+		ctx.Write(sum[:n])
+	}
+
+	for i := len(password); i != 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write([]byte{password[0]})
+		}
+	}
+
+	final := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 != 0 {
+			round.Write([]byte(password))
+		} else {
+			round.Write(final)
+		}
+		if i%3 != 0 {
+			round.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			round.Write([]byte(password))
+		}
+		if i&1 != 0 {
+			round.Write(final)
+		} else {
+			round.Write([]byte(password))
+		}
+		final = round.Sum(nil)
+	}
+
+	out := make([]byte, 0, 3+len(salt)+1+22)
+	out = append(out, "$1$"...)
+	out = append(out, salt...)
+	out = append(out, '$')
+	out = append(out, md5CryptTo64(uint32(final[0])<<16|uint32(final[6])<<8|uint32(final[12]), 4)...)
+	out = append(out, md5CryptTo64(uint32(final[1])<<16|uint32(final[7])<<8|uint32(final[13]), 4)...)
+	out = append(out, md5CryptTo64(uint32(final[2])<<16|uint32(final[8])<<8|uint32(final[14]), 4)...)
+	out = append(out, md5CryptTo64(uint32(final[3])<<16|uint32(final[9])<<8|uint32(final[15]), 4)...)
+	out = append(out, md5CryptTo64(uint32(final[4])<<16|uint32(final[10])<<8|uint32(final[5]), 4)...)
+	out = append(out, md5CryptTo64(uint32(final[11]), 2)...)
+	return string(out)
+}
+
+func md5CryptTo64(v uint32, n int) []byte {
+	out := make([]byte, n)
+	for i := 0; i < n; i++ {
+		out[i] = md5CryptAlphabet[v&0x3f]
+		v >>= 6
+	}
+	return out
+}
@@ -0,0 +1,204 @@
+package encryption
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Variant selects which Argon2 mode a PasswordHasher uses.
+type Variant string
+
+const (
+	// VariantArgon2i is recommended when the input may be attacker
+	// controlled and side-channel resistance to timing attacks matters
+	// more than resistance to GPU cracking.
+	VariantArgon2i Variant = "argon2i"
+
+	// VariantArgon2id is the default: it resists both GPU cracking and
+	// side-channel attacks, and is the variant recommended by the
+	// Argon2 RFC for password hashing.
+	VariantArgon2id Variant = "argon2id"
+)
+
+// Params holds the tunable cost parameters of an Argon2 hash, along with
+// enough information (Variant, SaltLen, KeyLen) to reproduce a
+// PasswordHasher from a previously stored hash.
+type Params struct {
+	Variant Variant
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultParams returns the package's current recommended Argon2id cost
+// parameters, matching the constants used by the legacy PasswordHash.
+func DefaultParams() Params {
+	return Params{
+		Variant: VariantArgon2id,
+		Time:    TIME,
+		Memory:  MEMORY,
+		Threads: THREADS,
+		SaltLen: SALTLEN,
+		KeyLen:  KEYLEN,
+	}
+}
+
+// PasswordHasher derives and verifies Argon2 password hashes under a
+// configurable set of Params, and encodes them as self-describing PHC
+// strings so the parameters used to create a hash travel with it: tuning
+// Params later does not invalidate hashes already stored.
+type PasswordHasher struct {
+	Params
+}
+
+// NewPasswordHasher returns a PasswordHasher using DefaultParams.
+func NewPasswordHasher() *PasswordHasher {
+	return &PasswordHasher{DefaultParams()}
+}
+
+// DeriveKey derives a raw key of h.KeyLen bytes from password and salt
+// under h.Params, without any PHC encoding. It is the building block
+// used by callers, such as encryption/keyfile, that need a bare
+// password-derived key rather than a stored, verifiable hash.
+func (h *PasswordHasher) DeriveKey(password string, salt []byte) []byte {
+	return h.derive(password, salt)
+}
+
+func (h *PasswordHasher) derive(password string, salt []byte) []byte {
+	switch h.Variant {
+	case VariantArgon2i:
+		return argon2.Key([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	default:
+		return argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	}
+}
+
+// HashPasswordEncoded derives a hash for password under h.Params and
+// returns it as a PHC string of the form
+// $argon2id$v=19$m=32768,t=8,p=4$<b64salt>$<b64hash>.
+func (h *PasswordHasher) HashPasswordEncoded(password string) (string, error) {
+	salt := GenerateRandomBytes(h.SaltLen)
+	hash := h.derive(password, salt)
+	return encodePHC(h.Params, salt, hash), nil
+}
+
+// VerifyPasswordEncoded checks password against a PHC string produced by
+// HashPasswordEncoded, re-deriving the hash with the parameters recorded
+// in encoded rather than h.Params, so hashes created under older
+// parameters remain verifiable after h.Params is tuned. The comparison
+// is constant-time.
+func (h *PasswordHasher) VerifyPasswordEncoded(password, encoded string) (bool, error) {
+	params, salt, wantHash, err := decodePHC(encoded)
+	if err != nil {
+		return false, err
+	}
+	got := (&PasswordHasher{params}).derive(password, salt)
+	return subtle.ConstantTimeCompare(got, wantHash) == 1, nil
+}
+
+func encodePHC(p Params, salt, hash []byte) string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		p.Variant, argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash))
+}
+
+func decodePHC(encoded string) (Params, []byte, []byte, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 || fields[0] != "" {
+		return Params{}, nil, nil, errors.New("encryption: malformed PHC string")
+	}
+
+	variant := Variant(fields[1])
+	if variant != VariantArgon2i && variant != VariantArgon2id {
+		return Params{}, nil, nil, fmt.Errorf("encryption: unsupported Argon2 variant %q", fields[1])
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil {
+		return Params{}, nil, nil, errors.New("encryption: malformed PHC version field")
+	}
+	if version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("encryption: unsupported Argon2 version %d", version)
+	}
+
+	p := Params{Variant: variant}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return Params{}, nil, nil, errors.New("encryption: malformed PHC parameters field")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("encryption: malformed PHC salt: %w", err)
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("encryption: malformed PHC hash: %w", err)
+	}
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(hash))
+
+	return p, salt, hash, nil
+}
+
+// maxBenchmarkMemory and maxBenchmarkTime bound Benchmark's search so it
+// terminates even if target is unreachable on the current host.
+const (
+	maxBenchmarkMemory uint32 = 1 << 20 // 1 GiB
+	maxBenchmarkTime   uint32 = 1 << 10
+)
+
+// Benchmark iteratively increases the memory and time cost of
+// DefaultParams, measuring actual hash time on the current host, until
+// a single hash takes roughly target. Memory is doubled first, since it
+// is the primary defense against GPU/ASIC cracking; once it hits
+// maxBenchmarkMemory, Time is doubled instead. It lets operators
+// auto-tune Argon2 cost per machine instead of hard-coding parameters
+// that may be too slow on small hosts or too fast (and therefore too
+// weak) on large ones.
+func Benchmark(target time.Duration) Params {
+	params := DefaultParams()
+	hasher := &PasswordHasher{params}
+	for {
+		salt := GenerateRandomBytes(params.SaltLen)
+		start := time.Now()
+		hasher.derive("benchmark-password", salt)
+		elapsed := time.Since(start)
+		if elapsed >= target {
+			break
+		}
+		next, atCeiling := stepBenchmarkParams(params)
+		if atCeiling {
+			// Both costs are already at their ceiling; stop instead
+			// of looping forever without reaching target.
+			return params
+		}
+		params = next
+		hasher.Params = params
+	}
+	return params
+}
+
+// stepBenchmarkParams returns the next cost parameters Benchmark should
+// try: memory is doubled first, and once it reaches maxBenchmarkMemory,
+// Time is doubled instead. atCeiling is true once both costs are
+// already at their maximum, in which case next equals p unchanged.
+func stepBenchmarkParams(p Params) (next Params, atCeiling bool) {
+	switch {
+	case p.Memory < maxBenchmarkMemory:
+		p.Memory *= 2
+	case p.Time < maxBenchmarkTime:
+		p.Time *= 2
+	default:
+		return p, true
+	}
+	return p, false
+}
@@ -0,0 +1,92 @@
+package encryption
+
+import "testing"
+
+func TestHashPasswordEncodedRoundTrip(t *testing.T) {
+	h := NewPasswordHasher()
+	encoded, err := h.HashPasswordEncoded("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPasswordEncoded: %v", err)
+	}
+
+	ok, err := h.VerifyPasswordEncoded("correct horse battery staple", encoded)
+	if err != nil {
+		t.Fatalf("VerifyPasswordEncoded: %v", err)
+	}
+	if !ok {
+		t.Fatal("VerifyPasswordEncoded: correct password did not verify")
+	}
+
+	ok, err = h.VerifyPasswordEncoded("wrong password", encoded)
+	if err != nil {
+		t.Fatalf("VerifyPasswordEncoded: %v", err)
+	}
+	if ok {
+		t.Fatal("VerifyPasswordEncoded: wrong password verified")
+	}
+}
+
+func TestVerifyPasswordEncodedAcrossRetunedParams(t *testing.T) {
+	original := &PasswordHasher{Params{
+		Variant: VariantArgon2id,
+		Time:    1,
+		Memory:  8 * 1024,
+		Threads: 1,
+		SaltLen: SALTLEN,
+		KeyLen:  KEYLEN,
+	}}
+	encoded, err := original.HashPasswordEncoded("hunter2")
+	if err != nil {
+		t.Fatalf("HashPasswordEncoded: %v", err)
+	}
+
+	// A hasher tuned with different cost parameters must still verify a
+	// hash produced under the old parameters, since the encoded string
+	// carries its own parameters.
+	retuned := &PasswordHasher{Params{
+		Variant: VariantArgon2id,
+		Time:    4,
+		Memory:  64 * 1024,
+		Threads: 2,
+		SaltLen: SALTLEN,
+		KeyLen:  KEYLEN,
+	}}
+	ok, err := retuned.VerifyPasswordEncoded("hunter2", encoded)
+	if err != nil {
+		t.Fatalf("VerifyPasswordEncoded: %v", err)
+	}
+	if !ok {
+		t.Fatal("VerifyPasswordEncoded: hash from retuned params did not verify")
+	}
+}
+
+func TestStepBenchmarkParamsScalesTimeAfterMemoryCeiling(t *testing.T) {
+	p := Params{Memory: maxBenchmarkMemory / 2, Time: 1}
+
+	p, atCeiling := stepBenchmarkParams(p)
+	if atCeiling {
+		t.Fatal("reported at ceiling while memory still below maxBenchmarkMemory")
+	}
+	if p.Memory != maxBenchmarkMemory {
+		t.Fatalf("memory not doubled to ceiling: got %d want %d", p.Memory, maxBenchmarkMemory)
+	}
+	if p.Time != 1 {
+		t.Fatalf("time changed before memory reached its ceiling: got %d", p.Time)
+	}
+
+	p, atCeiling = stepBenchmarkParams(p)
+	if atCeiling {
+		t.Fatal("reported at ceiling while time still below maxBenchmarkTime")
+	}
+	if p.Memory != maxBenchmarkMemory {
+		t.Fatalf("memory changed after reaching its ceiling: got %d", p.Memory)
+	}
+	if p.Time != 2 {
+		t.Fatalf("time not doubled once memory was at ceiling: got %d", p.Time)
+	}
+
+	p.Time = maxBenchmarkTime
+	if _, atCeiling := stepBenchmarkParams(p); !atCeiling {
+		t.Fatal("expected atCeiling once both memory and time are maxed out")
+	}
+}
@@ -0,0 +1,225 @@
+package encryption
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChunkSize is the size of a plaintext chunk encrypted by Writer and
+// decrypted by Reader. It is chosen to keep memory use bounded while
+// amortizing the per-chunk AEAD overhead.
+const ChunkSize = 64 * 1024
+
+// counterSize is the length, in bytes, of the big-endian chunk counter
+// embedded in each chunk nonce, plus one trailing last-chunk flag byte.
+const counterSize = 8 + 1
+
+// lastChunkFlag marks the final chunk of a stream so that Reader can
+// detect truncation: a stream cut short after a non-final chunk ends on
+// a nonce with flag 0, which Reader refuses to treat as complete.
+const lastChunkFlag = 1
+
+// Writer encrypts data written to it in fixed-size chunks, so that
+// arbitrarily large plaintexts can be encrypted without being held in
+// memory all at once. Each chunk is sealed with its own nonce, derived
+// from a random base nonce generated once per stream plus a 64-bit
+// chunk counter (the STREAM construction); the final chunk's nonce sets
+// a last-chunk flag so a truncated stream is detected as invalid rather
+// than silently accepted as complete.
+//
+// Writer must be closed to flush the final chunk.
+type Writer struct {
+	w         io.Writer
+	aead      *AEAD
+	baseNonce []byte
+	counter   uint64
+	buf       []byte
+	closed    bool
+}
+
+// NewWriter returns a Writer that seals data written to it and writes
+// the sealed chunks to w.
+func NewWriter(w io.Writer, aead *AEAD) (*Writer, error) {
+	baseNonce, err := newBaseNonce(aead)
+	if err != nil {
+		return nil, err
+	}
+	h := header{version: frameVersion, cipher: aead.cipher}
+	if _, err := w.Write(h.marshal()); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(baseNonce); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, baseNonce: baseNonce, buf: make([]byte, 0, ChunkSize)}, nil
+}
+
+// minStreamNonceSize is the smallest AEAD nonce size streaming will use.
+// The STREAM construction consumes counterSize bytes of every nonce for
+// the chunk counter and last-chunk flag, leaving the rest random; below
+// this size too little randomness would remain (e.g. AES-256-GCM's
+// 12-byte nonce would leave only 3 random bytes, a ~2^12-stream
+// birthday bound on (key, nonce) reuse, which is a full forgery break
+// for GCM). 16 bytes leaves at least 7 random bytes (56 bits).
+const minStreamNonceSize = 16
+
+func newBaseNonce(aead *AEAD) ([]byte, error) {
+	n, err := streamNonceRandomLen(aead)
+	if err != nil {
+		return nil, err
+	}
+	return GenerateRandomBytes(uint32(n)), nil
+}
+
+func streamNonceRandomLen(aead *AEAD) (int, error) {
+	size := aead.aead.NonceSize()
+	if size < minStreamNonceSize {
+		return 0, fmt.Errorf("encryption: cipher nonce size %d too small for streaming (need at least %d, e.g. use CipherXChaCha20Poly1305)", size, minStreamNonceSize)
+	}
+	return size - counterSize, nil
+}
+
+func chunkNonce(baseNonce []byte, counter uint64, last bool) []byte {
+	nonce := make([]byte, len(baseNonce)+counterSize)
+	n := copy(nonce, baseNonce)
+	binary.BigEndian.PutUint64(nonce[n:], counter)
+	if last {
+		nonce[len(nonce)-1] = lastChunkFlag
+	}
+	return nonce
+}
+
+// Write buffers p, sealing and emitting full chunks as they fill.
+func (sw *Writer) Write(p []byte) (int, error) {
+	if sw.closed {
+		return 0, errors.New("encryption: write to closed Writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(sw.buf[len(sw.buf):cap(sw.buf)], p)
+		sw.buf = sw.buf[:len(sw.buf)+n]
+		p = p[n:]
+		written += n
+		if len(sw.buf) == cap(sw.buf) {
+			if err := sw.sealChunk(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (sw *Writer) sealChunk(last bool) error {
+	nonce := chunkNonce(sw.baseNonce, sw.counter, last)
+	sealed := sw.aead.aead.Seal(nil, nonce, sw.buf, nil)
+	if _, err := sw.w.Write(sealed); err != nil {
+		return err
+	}
+	sw.counter++
+	sw.buf = sw.buf[:0]
+	return nil
+}
+
+// Close flushes the final chunk, marking it with the last-chunk flag.
+// It must be called exactly once, even if no data was written.
+func (sw *Writer) Close() error {
+	if sw.closed {
+		return nil
+	}
+	sw.closed = true
+	return sw.sealChunk(true)
+}
+
+// Reader decrypts a stream produced by Writer.
+type Reader struct {
+	r         *bufio.Reader
+	aead      *AEAD
+	baseNonce []byte
+	counter   uint64
+	sealedLen int
+	pending   []byte
+	done      bool
+}
+
+// NewReader returns a Reader that decrypts chunks read from r.
+func NewReader(r io.Reader, aead *AEAD) (*Reader, error) {
+	nonceRandomLen, err := streamNonceRandomLen(aead)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(r, ChunkSize+aead.aead.Overhead())
+	prefix := make([]byte, 2+nonceRandomLen)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, err
+	}
+	h, rest, err := parseHeader(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if h.cipher != aead.cipher {
+		return nil, errors.New("encryption: cipher mismatch")
+	}
+	return &Reader{
+		r:         br,
+		aead:      aead,
+		baseNonce: rest,
+		sealedLen: ChunkSize + aead.aead.Overhead(),
+	}, nil
+}
+
+// Read decrypts and returns plaintext bytes from the underlying stream,
+// satisfying io.Reader. It returns ErrUnauthenticated if a chunk fails
+// authentication, and a non-nil error if the stream ends without a
+// properly flagged final chunk (truncation).
+func (sr *Reader) Read(p []byte) (int, error) {
+	for len(sr.pending) == 0 {
+		if sr.done {
+			return 0, io.EOF
+		}
+		if err := sr.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, sr.pending)
+	sr.pending = sr.pending[n:]
+	return n, nil
+}
+
+func (sr *Reader) readChunk() error {
+	sealed := make([]byte, sr.sealedLen)
+	n, err := io.ReadFull(sr.r, sealed)
+	last := false
+	switch err {
+	case nil:
+		// Read a full chunk; peek to see whether more data follows.
+		if _, peekErr := sr.r.Peek(1); peekErr == io.EOF {
+			last = true
+		}
+	case io.ErrUnexpectedEOF:
+		sealed = sealed[:n]
+		last = true
+	case io.EOF:
+		return errors.New("encryption: truncated stream")
+	default:
+		return err
+	}
+
+	nonce := chunkNonce(sr.baseNonce, sr.counter, last)
+	plain, err := sr.aead.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return ErrUnauthenticated
+	}
+	sr.counter++
+	sr.pending = plain
+	sr.done = last
+	return nil
+}
+
+// Close releases any resources held by the Reader. It does not close
+// the underlying io.Reader.
+func (sr *Reader) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package encryption
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	key := GenerateRandomBytes(32)
+	aead, err := NewAEAD(key, CipherXChaCha20Poly1305)
+	if err != nil {
+		t.Fatalf("NewAEAD: %v", err)
+	}
+
+	// Exercise more than one chunk boundary.
+	message := bytes.Repeat([]byte("0123456789abcdef"), ChunkSize/8)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, aead)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(&buf, aead)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, message) {
+		t.Fatalf("round trip mismatch: got %d bytes, want %d bytes", len(got), len(message))
+	}
+}
+
+func TestReaderDetectsTruncation(t *testing.T) {
+	key := GenerateRandomBytes(32)
+	aead, err := NewAEAD(key, CipherXChaCha20Poly1305)
+	if err != nil {
+		t.Fatalf("NewAEAD: %v", err)
+	}
+
+	message := bytes.Repeat([]byte("x"), ChunkSize+10)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, aead)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Drop the final (flagged) chunk, leaving only the first full,
+	// non-final chunk: a truncated stream should not decrypt cleanly.
+	finalChunkLen := 10 + aead.aead.Overhead()
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-finalChunkLen])
+	r, err := NewReader(truncated, aead)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if _, err := io.ReadAll(r); err == nil {
+		t.Fatal("ReadAll of truncated stream succeeded, want an error")
+	}
+}
+
+func TestNewWriterRejectsSmallNonceCipher(t *testing.T) {
+	key := GenerateRandomBytes(32)
+	aead, err := NewAEAD(key, CipherAES256GCM)
+	if err != nil {
+		t.Fatalf("NewAEAD: %v", err)
+	}
+	if _, err := NewWriter(new(bytes.Buffer), aead); err == nil {
+		t.Fatal("NewWriter with a 12-byte-nonce cipher succeeded, want an error")
+	}
+}